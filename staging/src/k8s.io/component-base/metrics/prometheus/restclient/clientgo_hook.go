@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restclient
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/metrics"
+)
+
+// client-go's own metrics.Register is itself guarded by a sync.Once and
+// silently drops every call after the first in a process. Both the
+// Prometheus path (RegisterMetrics, in register.go) and the optional OTel path
+// (RegisterOTel, build-tagged "otel") need to contribute adapters to that
+// single call, in whichever order the embedder registers them, so each
+// adapter type client-go can observe through is a fan-out here: backends
+// can be added at any time via add(), and an observation is dispatched to
+// every backend present at the moment it arrives.
+
+type latencyObserver interface {
+	Observe(verb string, u url.URL, latency time.Duration)
+}
+
+type fanoutLatencyAdapter struct {
+	mu        sync.Mutex
+	observers []latencyObserver
+}
+
+func (f *fanoutLatencyAdapter) add(o latencyObserver) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.observers = append(f.observers, o)
+}
+
+func (f *fanoutLatencyAdapter) Observe(verb string, u url.URL, latency time.Duration) {
+	f.mu.Lock()
+	observers := append([]latencyObserver(nil), f.observers...)
+	f.mu.Unlock()
+	for _, o := range observers {
+		o.Observe(verb, u, latency)
+	}
+}
+
+type resultIncrementer interface {
+	Increment(code, method, host string)
+}
+
+type fanoutResultAdapter struct {
+	mu        sync.Mutex
+	observers []resultIncrementer
+}
+
+func (f *fanoutResultAdapter) add(o resultIncrementer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.observers = append(f.observers, o)
+}
+
+func (f *fanoutResultAdapter) Increment(code, method, host string) {
+	f.mu.Lock()
+	observers := append([]resultIncrementer(nil), f.observers...)
+	f.mu.Unlock()
+	for _, o := range observers {
+		o.Increment(code, method, host)
+	}
+}
+
+type ttlSetter interface {
+	Set(ttl *time.Duration)
+}
+
+type fanoutTTLAdapter struct {
+	mu        sync.Mutex
+	observers []ttlSetter
+}
+
+func (f *fanoutTTLAdapter) add(o ttlSetter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.observers = append(f.observers, o)
+}
+
+func (f *fanoutTTLAdapter) Set(ttl *time.Duration) {
+	f.mu.Lock()
+	observers := append([]ttlSetter(nil), f.observers...)
+	f.mu.Unlock()
+	for _, o := range observers {
+		o.Set(ttl)
+	}
+}
+
+type rotationObserver interface {
+	Observe(d time.Duration)
+}
+
+type fanoutRotationAdapter struct {
+	mu        sync.Mutex
+	observers []rotationObserver
+}
+
+func (f *fanoutRotationAdapter) add(o rotationObserver) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.observers = append(f.observers, o)
+}
+
+func (f *fanoutRotationAdapter) Observe(d time.Duration) {
+	f.mu.Lock()
+	observers := append([]rotationObserver(nil), f.observers...)
+	f.mu.Unlock()
+	for _, o := range observers {
+		o.Observe(d)
+	}
+}
+
+var (
+	latencyFanout  = &fanoutLatencyAdapter{}
+	resultFanout   = &fanoutResultAdapter{}
+	ttlFanout      = &fanoutTTLAdapter{}
+	rotationFanout = &fanoutRotationAdapter{}
+
+	clientGoHookOnce sync.Once
+)
+
+// installClientGoHook makes this package's single, one-shot call to
+// client-go's metrics.Register, installing the fan-out adapters above in
+// its place. It is safe to call from both RegisterMetrics and
+// RegisterOTel and in either order: whichever call arrives first performs
+// the registration, and backends can be appended to the fan-outs before
+// or after that happens since each fan-out reads its observer slice at
+// observation time, not at registration time.
+func installClientGoHook() {
+	clientGoHookOnce.Do(func() {
+		metrics.Register(metrics.RegisterOpts{
+			ClientCertTTL:         ttlFanout,
+			ClientCertRotationAge: rotationFanout,
+			RequestLatency:        latencyFanout,
+			RequestResult:         resultFanout,
+		})
+	})
+}
@@ -21,98 +21,28 @@ import (
 	"net/url"
 	"time"
 
-	"k8s.io/client-go/tools/metrics"
 	k8smetrics "k8s.io/component-base/metrics"
-	"k8s.io/component-base/metrics/legacyregistry"
 )
 
+// The metric instances themselves are constructed by RegisterMetrics, so
+// that their names, buckets, and stability level can be driven by a
+// RegisterOpts rather than hard-coded here. See register.go. They stay
+// nil, and the metrics unregistered, until something in the process
+// calls RegisterMetrics; this package does not do so itself.
 var (
 	// requestLatency is a Prometheus Summary metric type partitioned by
 	// "verb" and "url" labels. It is used for the rest client latency metrics.
-	requestLatency = k8smetrics.NewHistogramVec(
-		&k8smetrics.HistogramOpts{
-			Name:    "rest_client_request_duration_seconds",
-			Help:    "Request latency in seconds. Broken down by verb and URL.",
-			Buckets: k8smetrics.ExponentialBuckets(0.001, 2, 10),
-		},
-		[]string{"verb", "url"},
-	)
+	requestLatency *k8smetrics.HistogramVec
 
 	// deprecatedRequestLatency is deprecated, please use requestLatency.
-	deprecatedRequestLatency = k8smetrics.NewHistogramVec(
-		&k8smetrics.HistogramOpts{
-			Name:              "rest_client_request_latency_seconds",
-			Help:              "Request latency in seconds. Broken down by verb and URL.",
-			Buckets:           k8smetrics.ExponentialBuckets(0.001, 2, 10),
-			DeprecatedVersion: "1.14.0",
-		},
-		[]string{"verb", "url"},
-	)
-
-	requestResult = k8smetrics.NewCounterVec(
-		&k8smetrics.CounterOpts{
-			Name: "rest_client_requests_total",
-			Help: "Number of HTTP requests, partitioned by status code, method, and host.",
-		},
-		[]string{"code", "method", "host"},
-	)
-
-	execPluginCertTTL = k8smetrics.NewGauge(
-		&k8smetrics.GaugeOpts{
-			Name: "rest_client_exec_plugin_ttl_seconds",
-			Help: "Gauge of the shortest TTL (time-to-live) of the client " +
-				"certificate(s) managed by the auth exec plugin. The value " +
-				"is in seconds until certificate expiry. If auth exec " +
-				"plugins are unused or manage no TLS certificates, the " +
-				"value will be +INF.",
-		},
-	)
-
-	execPluginCertRotation = k8smetrics.NewHistogram(
-		&k8smetrics.HistogramOpts{
-			Name: "rest_client_exec_plugin_certificate_rotation_age",
-			Help: "Histogram of the number of seconds the last auth exec " +
-				"plugin client certificate lived before being rotated. " +
-				"If auth exec plugin client certificates are unused, " +
-				"histogram will contain no data.",
-			// There are three sets of ranges these buckets intend to capture:
-			//   - 10-60 minutes: captures a rotation cadence which is
-			//     happening too quickly.
-			//   - 4 hours - 1 month: captures an ideal rotation cadence.
-			//   - 3 months - 4 years: captures a rotation cadence which is
-			//     is probably too slow or much too slow.
-			Buckets: []float64{
-				600,       // 10 minutes
-				1800,      // 30 minutes
-				3600,      // 1  hour
-				14400,     // 4  hours
-				86400,     // 1  day
-				604800,    // 1  week
-				2592000,   // 1  month
-				7776000,   // 3  months
-				15552000,  // 6  months
-				31104000,  // 1  year
-				124416000, // 4  years
-			},
-		},
-	)
-)
+	deprecatedRequestLatency *k8smetrics.HistogramVec
 
-func init() {
-	execPluginCertTTL.Set(math.Inf(1)) // Initialize TTL to +INF
-
-	legacyregistry.MustRegister(requestLatency)
-	legacyregistry.MustRegister(deprecatedRequestLatency)
-	legacyregistry.MustRegister(requestResult)
-	legacyregistry.MustRegister(execPluginCertTTL)
-	legacyregistry.MustRegister(execPluginCertRotation)
-	metrics.Register(metrics.RegisterOpts{
-		ClientCertTTL:         &ttlAdapter{m: execPluginCertTTL},
-		ClientCertRotationAge: &rotationAdapter{m: execPluginCertRotation},
-		RequestLatency:        &latencyAdapter{m: requestLatency, dm: deprecatedRequestLatency},
-		RequestResult:         &resultAdapter{requestResult},
-	})
-}
+	requestResult *k8smetrics.CounterVec
+
+	execPluginCertTTL *k8smetrics.Gauge
+
+	execPluginCertRotation *k8smetrics.Histogram
+)
 
 type latencyAdapter struct {
 	m  *k8smetrics.HistogramVec
@@ -120,8 +50,9 @@ type latencyAdapter struct {
 }
 
 func (l *latencyAdapter) Observe(verb string, u url.URL, latency time.Duration) {
-	l.m.WithLabelValues(verb, u.String()).Observe(latency.Seconds())
-	l.dm.WithLabelValues(verb, u.String()).Observe(latency.Seconds())
+	label := urlNormalizer.Normalize(verb, u)
+	l.m.WithLabelValues(verb, label).Observe(latency.Seconds())
+	l.dm.WithLabelValues(verb, label).Observe(latency.Seconds())
 }
 
 type resultAdapter struct {
@@ -0,0 +1,167 @@
+//go:build otel
+// +build otel
+
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This file is built only when the "otel" build tag is set, so that
+// consumers who don't want the OpenTelemetry dependency never pull it in.
+// RegisterOTel installs adapters that record the same rest client metrics
+// as the Prometheus path into an otel.Meter, and can be installed on its
+// own or alongside the Prometheus registration for dual export during a
+// migration.
+
+package restclient
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// otelInstruments names the OTel instruments this package exports, so that
+// the mapping from Prometheus metric name to OTel instrument name is
+// documented in one place:
+//
+//	rest_client_request_duration_seconds      -> rest_client.request.duration        (Float64Histogram, seconds)
+//	rest_client_requests_total                -> rest_client.requests                 (Float64Counter)
+//	rest_client_exec_plugin_ttl_seconds        -> rest_client.exec_plugin.cert_ttl     (Float64ObservableGauge, seconds)
+//	rest_client_exec_plugin_certificate_rotation_age -> rest_client.exec_plugin.cert_rotation_age (Float64Histogram, seconds)
+const (
+	otelRequestDurationName    = "rest_client.request.duration"
+	otelRequestsTotalName      = "rest_client.requests"
+	otelExecPluginCertTTLName  = "rest_client.exec_plugin.cert_ttl"
+	otelExecPluginRotationName = "rest_client.exec_plugin.cert_rotation_age"
+)
+
+// RegisterOTel installs OTel-backed adapters, built from meterProvider,
+// alongside whatever is already feeding client-go's metrics.Register hook
+// (see installClientGoHook in clientgo_hook.go). It can be called before,
+// after, or instead of the Prometheus-backed RegisterMetrics: both append
+// their adapters to the same shared fan-outs rather than each trying to
+// call client-go's metrics.Register directly, since that function is
+// itself one-shot and would silently drop whichever call came second.
+func RegisterOTel(meterProvider metric.MeterProvider) {
+	meter := meterProvider.Meter("k8s.io/component-base/metrics/prometheus/restclient")
+
+	requestDuration, err := meter.Float64Histogram(
+		otelRequestDurationName,
+		metric.WithDescription("Request latency in seconds. Broken down by verb and URL."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return
+	}
+
+	requestsTotal, err := meter.Float64Counter(
+		otelRequestsTotalName,
+		metric.WithDescription("Number of HTTP requests, partitioned by status code, method, and host."),
+	)
+	if err != nil {
+		return
+	}
+
+	certRotation, err := meter.Float64Histogram(
+		otelExecPluginRotationName,
+		metric.WithDescription("Number of seconds the last auth exec plugin client certificate lived before being rotated."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return
+	}
+
+	ttl := &otelTTLAdapter{}
+	_, err = meter.Float64ObservableGauge(
+		otelExecPluginCertTTLName,
+		metric.WithDescription("Shortest TTL (time-to-live) of the client certificate(s) managed by the auth exec plugin, in seconds."),
+		metric.WithUnit("s"),
+		metric.WithFloat64Callback(ttl.callback),
+	)
+	if err != nil {
+		return
+	}
+
+	ttlFanout.add(ttl)
+	rotationFanout.add(&otelRotationAdapter{m: certRotation})
+	latencyFanout.add(&otelLatencyAdapter{m: requestDuration})
+	resultFanout.add(&otelResultAdapter{m: requestsTotal})
+	installClientGoHook()
+}
+
+type otelLatencyAdapter struct {
+	m metric.Float64Histogram
+}
+
+func (l *otelLatencyAdapter) Observe(verb string, u url.URL, latency time.Duration) {
+	label := urlNormalizer.Normalize(verb, u)
+	l.m.Record(context.Background(), latency.Seconds(),
+		metric.WithAttributes(attribute.String("verb", verb), attribute.String("url", label)))
+}
+
+type otelResultAdapter struct {
+	m metric.Float64Counter
+}
+
+func (r *otelResultAdapter) Increment(code, method, host string) {
+	r.m.Add(context.Background(), 1,
+		metric.WithAttributes(
+			attribute.String("code", code),
+			attribute.String("method", method),
+			attribute.String("host", host),
+		))
+}
+
+// otelTTLAdapter bridges the push-style ClientCertTTL.Set callback used by
+// client-go to the pull-style ObservableGauge callback OTel expects.
+// client-go passes a nil ttl for "no certificate managed", which the
+// Prometheus ttlAdapter reports as math.Inf(1). That has no sensible OTel
+// observation, so for a nil ttl (or before any Set) the callback observes
+// nothing rather than reporting +Inf.
+type otelTTLAdapter struct {
+	mu  sync.Mutex
+	ttl *time.Duration
+	set bool
+}
+
+func (e *otelTTLAdapter) Set(ttl *time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ttl = ttl
+	e.set = true
+}
+
+func (e *otelTTLAdapter) callback(_ context.Context, o metric.Float64Observer) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.set || e.ttl == nil || math.IsInf(e.ttl.Seconds(), 1) {
+		return nil
+	}
+	o.Observe(e.ttl.Seconds())
+	return nil
+}
+
+type otelRotationAdapter struct {
+	m metric.Float64Histogram
+}
+
+func (r *otelRotationAdapter) Observe(d time.Duration) {
+	r.m.Record(context.Background(), d.Seconds())
+}
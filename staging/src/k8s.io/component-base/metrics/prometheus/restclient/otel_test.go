@@ -0,0 +1,142 @@
+//go:build otel
+// +build otel
+
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restclient
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// resetFanoutsForTest clears every observer the shared clientgo_hook.go
+// fan-outs have accumulated so far, so that a test calling RegisterOTel
+// only ever observes the adapters it installed itself. Without this, the
+// fan-outs (deliberately process-wide singletons, so RegisterMetrics and
+// RegisterOTel can share client-go's one-shot hook regardless of call
+// order) would keep every prior test's adapters around too, each still
+// bound to that test's already-collected reader.
+func resetFanoutsForTest(t *testing.T) {
+	t.Helper()
+
+	latencyFanout.mu.Lock()
+	latencyFanout.observers = nil
+	latencyFanout.mu.Unlock()
+
+	resultFanout.mu.Lock()
+	resultFanout.observers = nil
+	resultFanout.mu.Unlock()
+
+	ttlFanout.mu.Lock()
+	ttlFanout.observers = nil
+	ttlFanout.mu.Unlock()
+
+	rotationFanout.mu.Lock()
+	rotationFanout.observers = nil
+	rotationFanout.mu.Unlock()
+}
+
+// collect runs a fresh collection against reader and returns the
+// resource metrics it observed, for simple presence/shape assertions.
+func collect(t *testing.T, reader *sdkmetric.ManualReader) metricdata.ResourceMetrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() returned error: %v", err)
+	}
+	return rm
+}
+
+func findMetric(rm metricdata.ResourceMetrics, name string) (metricdata.Metrics, bool) {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return metricdata.Metrics{}, false
+}
+
+func TestRegisterOTel(t *testing.T) {
+	resetFanoutsForTest(t)
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	RegisterOTel(mp)
+
+	latencyFanout.Observe("GET", url.URL{Path: "/api/v1/namespaces/default/pods/foo"}, 42*time.Millisecond)
+	resultFanout.Increment("200", "GET", "localhost")
+
+	rm := collect(t, reader)
+
+	duration, ok := findMetric(rm, otelRequestDurationName)
+	if !ok {
+		t.Fatalf("expected instrument %q to be recorded, got %+v", otelRequestDurationName, rm)
+	}
+	hist, ok := duration.Data.(metricdata.Histogram[float64])
+	if !ok || len(hist.DataPoints) != 1 {
+		t.Fatalf("expected one histogram data point for %q, got %+v", otelRequestDurationName, duration.Data)
+	}
+
+	if _, ok := findMetric(rm, otelRequestsTotalName); !ok {
+		t.Fatalf("expected instrument %q to be recorded, got %+v", otelRequestsTotalName, rm)
+	}
+}
+
+// TestOTelTTLAdapterSkipsInfSentinel verifies that the exec-plugin "no
+// certificate managed" sentinel (a nil *time.Duration, which the
+// Prometheus ttlAdapter reports as +Inf) produces no observation at all,
+// rather than an OTel data point of +Inf.
+func TestOTelTTLAdapterSkipsInfSentinel(t *testing.T) {
+	resetFanoutsForTest(t)
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	RegisterOTel(mp)
+
+	ttlFanout.Set(nil)
+
+	rm := collect(t, reader)
+	if m, ok := findMetric(rm, otelExecPluginCertTTLName); ok {
+		if gauge, ok := m.Data.(metricdata.Gauge[float64]); ok && len(gauge.DataPoints) != 0 {
+			t.Fatalf("expected no data points for %q when ttl is nil, got %+v", otelExecPluginCertTTLName, gauge.DataPoints)
+		}
+	}
+
+	ttl := 5 * time.Minute
+	ttlFanout.Set(&ttl)
+
+	rm = collect(t, reader)
+	m, ok := findMetric(rm, otelExecPluginCertTTLName)
+	if !ok {
+		t.Fatalf("expected instrument %q to be recorded once a non-nil ttl is set", otelExecPluginCertTTLName)
+	}
+	gauge, ok := m.Data.(metricdata.Gauge[float64])
+	if !ok || len(gauge.DataPoints) != 1 {
+		t.Fatalf("expected one gauge data point for %q, got %+v", otelExecPluginCertTTLName, m.Data)
+	}
+	if got, want := gauge.DataPoints[0].Value, ttl.Seconds(); got != want {
+		t.Fatalf("got ttl gauge value %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restclient
+
+import (
+	"math"
+	"sync"
+
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// defaultRequestLatencyBuckets are the bucket boundaries used for
+// rest_client_request_duration_seconds and rest_client_request_latency_seconds
+// unless RegisterOpts.RequestLatencyBuckets overrides them.
+var defaultRequestLatencyBuckets = k8smetrics.ExponentialBuckets(0.001, 2, 10)
+
+// defaultExecPluginCertRotationBuckets are the bucket boundaries used for
+// rest_client_exec_plugin_certificate_rotation_age unless
+// RegisterOpts.ExecPluginCertRotationBuckets overrides them. There are
+// three sets of ranges these buckets intend to capture:
+//   - 10-60 minutes: captures a rotation cadence which is happening too
+//     quickly.
+//   - 4 hours - 1 month: captures an ideal rotation cadence.
+//   - 3 months - 4 years: captures a rotation cadence which is probably
+//     too slow or much too slow.
+var defaultExecPluginCertRotationBuckets = []float64{
+	600,       // 10 minutes
+	1800,      // 30 minutes
+	3600,      // 1  hour
+	14400,     // 4  hours
+	86400,     // 1  day
+	604800,    // 1  week
+	2592000,   // 1  month
+	7776000,   // 3  months
+	15552000,  // 6  months
+	31104000,  // 1  year
+	124416000, // 4  years
+}
+
+// RegisterOpts configures how the metrics in this package are constructed
+// and registered. The zero value reproduces this package's historical
+// behavior: the default metric names and bucket boundaries, at ALPHA
+// stability, registered into the global legacy registry.
+type RegisterOpts struct {
+	// Registerer is the registry the metrics are registered into. If nil,
+	// the metrics are registered into the global legacy registry via
+	// legacyregistry.MustRegister, which is what this package did before
+	// RegisterMetrics existed. Embedders that do not want to pollute the
+	// global legacy registry (aggregated apiservers, controller-runtime
+	// managers, etc.) should supply their own k8smetrics.KubeRegistry,
+	// such as one returned by k8smetrics.NewKubeRegistry().
+	Registerer k8smetrics.KubeRegistry
+
+	// Subsystem, if non-empty, is set as the Subsystem on every metric
+	// registered by this package, so e.g. "rest_client_requests_total"
+	// becomes "mysubsystem_rest_client_requests_total".
+	Subsystem string
+
+	// StabilityLevel is applied to every metric registered by this
+	// package. It defaults to k8smetrics.ALPHA.
+	StabilityLevel k8smetrics.StabilityLevel
+
+	// RequestLatencyBuckets overrides the bucket boundaries used by
+	// rest_client_request_duration_seconds and its deprecated sibling
+	// rest_client_request_latency_seconds. Defaults to
+	// defaultRequestLatencyBuckets.
+	RequestLatencyBuckets []float64
+
+	// ExecPluginCertRotationBuckets overrides the bucket boundaries used
+	// by rest_client_exec_plugin_certificate_rotation_age. Defaults to
+	// defaultExecPluginCertRotationBuckets.
+	ExecPluginCertRotationBuckets []float64
+
+	// RateLimiterLatencyBuckets overrides the bucket boundaries used by
+	// rest_client_rate_limiter_duration_seconds. Defaults to
+	// defaultRequestLatencyBuckets. Only meaningful when
+	// ClientSaturationMetrics is set.
+	RateLimiterLatencyBuckets []float64
+
+	// ClientSaturationMetrics registers rest_client_rate_limiter_duration_seconds,
+	// rest_client_request_retries_total, and rest_client_in_flight_requests
+	// alongside the rest of this package's metrics. It defaults to false:
+	// nothing in this tree feeds these adapters yet (client-go's rate
+	// limiter, transport, and round tripper, which would call
+	// rateLimiterAdapter/retryAdapter/inFlightAdapter, live outside this
+	// package — see ratelimit_metrics.go), and a permanently-empty series
+	// is worse for dashboards and alerts than an absent one. Only set
+	// this once something in the process actually drives those adapters.
+	ClientSaturationMetrics bool
+}
+
+func (o RegisterOpts) stabilityLevel() k8smetrics.StabilityLevel {
+	if o.StabilityLevel == "" {
+		return k8smetrics.ALPHA
+	}
+	return o.StabilityLevel
+}
+
+func (o RegisterOpts) requestLatencyBuckets() []float64 {
+	if o.RequestLatencyBuckets != nil {
+		return o.RequestLatencyBuckets
+	}
+	return defaultRequestLatencyBuckets
+}
+
+func (o RegisterOpts) execPluginCertRotationBuckets() []float64 {
+	if o.ExecPluginCertRotationBuckets != nil {
+		return o.ExecPluginCertRotationBuckets
+	}
+	return defaultExecPluginCertRotationBuckets
+}
+
+func (o RegisterOpts) rateLimiterLatencyBuckets() []float64 {
+	if o.RateLimiterLatencyBuckets != nil {
+		return o.RateLimiterLatencyBuckets
+	}
+	return defaultRequestLatencyBuckets
+}
+
+// mustRegister registers m into opts.Registerer, falling back to the
+// global legacy registry when opts.Registerer is nil.
+func (o RegisterOpts) mustRegister(m k8smetrics.Registerable) {
+	if o.Registerer != nil {
+		o.Registerer.MustRegister(m)
+		return
+	}
+	legacyregistry.MustRegister(m)
+}
+
+var registerOnce sync.Once
+
+// RegisterMetrics builds the rest client metrics described by opts,
+// registers them, and wires them into client-go's metrics.Register hook
+// via installClientGoHook (see clientgo_hook.go). It may only be called
+// once per process; subsequent calls are silently ignored.
+//
+// This package does not call RegisterMetrics from an init() of its own.
+// Go always runs an imported package's init() before any code in the
+// importer runs, so an unconditional init() here would win the
+// registerOnce race against an embedder's own RegisterMetrics(opts) call
+// every time, no matter how early the embedder called it — silently
+// discarding whatever Registerer, Subsystem, or buckets it asked for.
+// That makes RegisterOpts pointless, so metrics from this package are
+// registered only when something calls RegisterMetrics explicitly.
+// Callers that relied on the old zero-config behavior (metrics appearing
+// in the legacy registry just from importing this package) need to call
+// RegisterMetrics(RegisterOpts{}) themselves to get it back.
+func RegisterMetrics(opts RegisterOpts) {
+	registerOnce.Do(func() {
+		stability := opts.stabilityLevel()
+
+		requestLatency = k8smetrics.NewHistogramVec(
+			&k8smetrics.HistogramOpts{
+				Subsystem:      opts.Subsystem,
+				Name:           "rest_client_request_duration_seconds",
+				Help:           "Request latency in seconds. Broken down by verb and URL.",
+				Buckets:        opts.requestLatencyBuckets(),
+				StabilityLevel: stability,
+			},
+			[]string{"verb", "url"},
+		)
+
+		deprecatedRequestLatency = k8smetrics.NewHistogramVec(
+			&k8smetrics.HistogramOpts{
+				Subsystem:         opts.Subsystem,
+				Name:              "rest_client_request_latency_seconds",
+				Help:              "Request latency in seconds. Broken down by verb and URL.",
+				Buckets:           opts.requestLatencyBuckets(),
+				StabilityLevel:    stability,
+				DeprecatedVersion: "1.14.0",
+			},
+			[]string{"verb", "url"},
+		)
+
+		requestResult = k8smetrics.NewCounterVec(
+			&k8smetrics.CounterOpts{
+				Subsystem:      opts.Subsystem,
+				Name:           "rest_client_requests_total",
+				Help:           "Number of HTTP requests, partitioned by status code, method, and host.",
+				StabilityLevel: stability,
+			},
+			[]string{"code", "method", "host"},
+		)
+
+		execPluginCertTTL = k8smetrics.NewGauge(
+			&k8smetrics.GaugeOpts{
+				Subsystem: opts.Subsystem,
+				Name:      "rest_client_exec_plugin_ttl_seconds",
+				Help: "Gauge of the shortest TTL (time-to-live) of the client " +
+					"certificate(s) managed by the auth exec plugin. The value " +
+					"is in seconds until certificate expiry. If auth exec " +
+					"plugins are unused or manage no TLS certificates, the " +
+					"value will be +INF.",
+				StabilityLevel: stability,
+			},
+		)
+
+		execPluginCertRotation = k8smetrics.NewHistogram(
+			&k8smetrics.HistogramOpts{
+				Subsystem: opts.Subsystem,
+				Name:      "rest_client_exec_plugin_certificate_rotation_age",
+				Help: "Histogram of the number of seconds the last auth exec " +
+					"plugin client certificate lived before being rotated. " +
+					"If auth exec plugin client certificates are unused, " +
+					"histogram will contain no data.",
+				Buckets:        opts.execPluginCertRotationBuckets(),
+				StabilityLevel: stability,
+			},
+		)
+
+		execPluginCertTTL.Set(math.Inf(1)) // Initialize TTL to +INF
+
+		opts.mustRegister(requestLatency)
+		opts.mustRegister(deprecatedRequestLatency)
+		opts.mustRegister(requestResult)
+		opts.mustRegister(execPluginCertTTL)
+		opts.mustRegister(execPluginCertRotation)
+
+		// Gated behind ClientSaturationMetrics: see its doc comment on
+		// RegisterOpts for why these aren't registered unconditionally.
+		// Even once registered, nothing feeds them yet, since that
+		// requires corresponding fields on client-go's own
+		// tools/metrics.RegisterOpts and call sites in its rate limiter,
+		// transport, and round tripper, none of which live in this
+		// package (see ratelimit_metrics.go).
+		if opts.ClientSaturationMetrics {
+			rateLimiterLatency = k8smetrics.NewHistogramVec(
+				&k8smetrics.HistogramOpts{
+					Subsystem:      opts.Subsystem,
+					Name:           "rest_client_rate_limiter_duration_seconds",
+					Help:           "Client side rate limiter latency in seconds. Broken down by verb and host.",
+					Buckets:        opts.rateLimiterLatencyBuckets(),
+					StabilityLevel: stability,
+				},
+				[]string{"verb", "host"},
+			)
+
+			requestRetry = k8smetrics.NewCounterVec(
+				&k8smetrics.CounterOpts{
+					Subsystem:      opts.Subsystem,
+					Name:           "rest_client_request_retries_total",
+					Help:           "Number of request retries, partitioned by status code, method, host, and retry reason.",
+					StabilityLevel: stability,
+				},
+				[]string{"code", "method", "host", "reason"},
+			)
+
+			clientInFlight = k8smetrics.NewGaugeVec(
+				&k8smetrics.GaugeOpts{
+					Subsystem:      opts.Subsystem,
+					Name:           "rest_client_in_flight_requests",
+					Help:           "Number of requests currently in flight, partitioned by host.",
+					StabilityLevel: stability,
+				},
+				[]string{"host"},
+			)
+
+			opts.mustRegister(rateLimiterLatency)
+			opts.mustRegister(requestRetry)
+			opts.mustRegister(clientInFlight)
+		}
+
+		// These adapters are appended to the shared fan-outs rather than
+		// passed directly to metrics.Register, so that RegisterOTel can
+		// add its own adapters alongside them instead of racing for the
+		// one-shot metrics.Register call (see clientgo_hook.go).
+		latencyFanout.add(&latencyAdapter{m: requestLatency, dm: deprecatedRequestLatency})
+		resultFanout.add(&resultAdapter{requestResult})
+		ttlFanout.add(&ttlAdapter{m: execPluginCertTTL})
+		rotationFanout.add(&rotationAdapter{m: execPluginCertRotation})
+		installClientGoHook()
+	})
+}
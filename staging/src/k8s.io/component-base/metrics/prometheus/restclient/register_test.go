@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restclient
+
+import (
+	"testing"
+
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+// TestRegisterMetricsRespectsProvidedRegistry guards against a regression
+// where registerOnce is consumed by something other than the embedder's
+// own RegisterMetrics(opts) call: it asserts that a private Registerer and
+// Subsystem actually take effect, and that doing so keeps this package's
+// metrics out of the global legacy registry entirely. This is the only
+// RegisterMetrics call in this package's test binary, since registerOnce
+// only lets the first one win.
+func TestRegisterMetricsRespectsProvidedRegistry(t *testing.T) {
+	registry := k8smetrics.NewKubeRegistry()
+
+	RegisterMetrics(RegisterOpts{
+		Registerer: registry,
+		Subsystem:  "embedder",
+	})
+	// A vector metric only gathers a series once a label set is observed.
+	resultFanout.Increment("200", "GET", "localhost")
+
+	count, err := testutil.GatherAndCount(registry, "embedder_rest_client_requests_total")
+	if err != nil {
+		t.Fatalf("GatherAndCount on private registry: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected embedder_rest_client_requests_total to be registered on the private registry, got count=%d", count)
+	}
+
+	count, err = testutil.GatherAndCount(legacyregistry.DefaultGatherer, "embedder_rest_client_requests_total", "rest_client_requests_total")
+	if err != nil {
+		t.Fatalf("GatherAndCount on legacy registry: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected RegisterMetrics called with a private Registerer to leave the legacy registry untouched, found %d series", count)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restclient
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// unknownPathLabel is substituted for any request path this package's
+// DefaultURLNormalizer cannot confidently classify, so that unexpected
+// paths collapse into a single bounded-cardinality series rather than
+// leaking raw request paths into the "url" label.
+const unknownPathLabel = "<unknown>"
+
+// URLNormalizer reduces a request URL to a bounded-cardinality label value
+// suitable for use on a Prometheus metric. Implementations must not return
+// a value that varies with the namespace, name, or resourceVersion of the
+// resource being accessed.
+type URLNormalizer interface {
+	// Normalize returns the label value to record for a request with the
+	// given verb made against u. The query string is never significant
+	// and implementations should ignore it.
+	Normalize(verb string, u url.URL) string
+}
+
+// urlNormalizer is the process-wide normalizer consulted by latencyAdapter.
+// It defaults to DefaultURLNormalizer and can be swapped exactly once via
+// SetURLNormalizer.
+var urlNormalizer URLNormalizer = DefaultURLNormalizer{}
+
+var setNormalizerOnce sync.Once
+
+// SetURLNormalizer overrides the URLNormalizer used when recording the
+// rest_client_request_duration_seconds and rest_client_request_latency_seconds
+// metrics. It may only be called once per process: the "url" label is part
+// of the metric's identity, so letting a normalizer change after metrics
+// have already been emitted under it would silently redefine what an
+// existing series means rather than starting a new one.
+func SetURLNormalizer(n URLNormalizer) {
+	setNormalizerOnce.Do(func() {
+		urlNormalizer = n
+	})
+}
+
+// DefaultURLNormalizer collapses Kubernetes API paths into resource
+// templates (e.g. "api/v1/namespaces/{namespace}/pods/{name}", with no
+// leading slash) so that the
+// "url" label has cardinality bounded by the set of resource types the
+// client talks to, rather than growing with every namespace, resource name,
+// or watch resourceVersion. Paths it cannot recognize are reported as
+// unknownPathLabel.
+type DefaultURLNormalizer struct{}
+
+// Normalize implements URLNormalizer.
+func (DefaultURLNormalizer) Normalize(verb string, u url.URL) string {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if template, ok := normalizePath(segments); ok {
+		return template
+	}
+	return unknownPathLabel
+}
+
+// normalizePath walks a split, cleaned request path and rewrites it into a
+// resource template. It understands:
+//
+//	/api/{version}/...
+//	/apis/{group}/{version}/...
+//
+// followed by an optional "namespaces/{namespace}" pair, a resource plural,
+// an optional "{name}", and an optional subresource
+// (proxy/exec/portforward/log/...) with any segments after it. The
+// subresource name is not checked against a list of known names. Paths
+// not rooted at "api" or "apis", or too short to hold a version (and, for
+// "apis", a group), are reported as unrecognized so the caller falls back
+// to unknownPathLabel.
+func normalizePath(segments []string) (string, bool) {
+	if len(segments) == 0 || segments[0] == "" {
+		return "", false
+	}
+
+	var out []string
+	var rest []string
+
+	switch segments[0] {
+	case "api":
+		if len(segments) < 2 {
+			return "", false
+		}
+		out = []string{"api", segments[1]}
+		rest = segments[2:]
+	case "apis":
+		if len(segments) < 3 {
+			return "", false
+		}
+		out = []string{"apis", segments[1], segments[2]}
+		rest = segments[3:]
+	default:
+		return "", false
+	}
+
+	if len(rest) >= 2 && rest[0] == "namespaces" {
+		out = append(out, "namespaces", "{namespace}")
+		rest = rest[2:]
+	}
+
+	if len(rest) == 0 {
+		return strings.Join(out, "/"), true
+	}
+
+	// resource plural, e.g. "pods", "deployments"
+	out = append(out, rest[0])
+	rest = rest[1:]
+	if len(rest) == 0 {
+		return strings.Join(out, "/"), true
+	}
+
+	// resource name. Note this does not special-case the deprecated
+	// pre-1.0 "/api/v1/watch/{plural}" path style, where this segment is
+	// actually the resource name being watched rather than a "{name}":
+	// those paths are mislabeled (e.g. "watch" is read as the resource
+	// plural) but still collapse to a handful of bounded-cardinality
+	// templates, which is what this normalizer cares about.
+	out = append(out, "{name}")
+	rest = rest[1:]
+	if len(rest) == 0 {
+		return strings.Join(out, "/"), true
+	}
+
+	// The next segment is the subresource name (proxy, exec, portforward,
+	// log, status, ...). Most subresources address a fixed, bounded set
+	// of paths and are kept verbatim. "proxy" is different: everything
+	// after it is an arbitrary, caller-chosen target path through the
+	// proxy, which is exactly the unbounded cardinality this normalizer
+	// exists to avoid, so it is collapsed to the subresource name alone
+	// and any further segments are dropped.
+	subresource := rest[0]
+	out = append(out, subresource)
+	if subresource == "proxy" {
+		return strings.Join(out, "/"), true
+	}
+	rest = rest[1:]
+
+	out = append(out, rest...)
+	return strings.Join(out, "/"), true
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restclient
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestDefaultURLNormalizer(t *testing.T) {
+	cases := []struct {
+		name string
+		verb string
+		path string
+		want string
+	}{
+		{
+			name: "core namespaced resource",
+			verb: "GET",
+			path: "/api/v1/namespaces/default/pods/foo",
+			want: "api/v1/namespaces/{namespace}/pods/{name}",
+		},
+		{
+			name: "core namespaced list",
+			verb: "LIST",
+			path: "/api/v1/namespaces/default/pods",
+			want: "api/v1/namespaces/{namespace}/pods",
+		},
+		{
+			name: "core cluster-scoped resource",
+			verb: "GET",
+			path: "/api/v1/nodes/foo",
+			want: "api/v1/nodes/{name}",
+		},
+		{
+			name: "named group resource",
+			verb: "GET",
+			path: "/apis/apps/v1/namespaces/default/deployments/foo",
+			want: "apis/apps/v1/namespaces/{namespace}/deployments/{name}",
+		},
+		{
+			name: "watch on a named resource",
+			verb: "WATCH",
+			path: "/api/v1/namespaces/default/pods/foo",
+			want: "api/v1/namespaces/{namespace}/pods/{name}",
+		},
+		{
+			name: "status subresource",
+			verb: "PATCH",
+			path: "/api/v1/namespaces/default/pods/foo/status",
+			want: "api/v1/namespaces/{namespace}/pods/{name}/status",
+		},
+		{
+			name: "exec subresource",
+			verb: "POST",
+			path: "/api/v1/namespaces/default/pods/foo/exec",
+			want: "api/v1/namespaces/{namespace}/pods/{name}/exec",
+		},
+		{
+			name: "portforward subresource",
+			verb: "POST",
+			path: "/api/v1/namespaces/default/pods/foo/portforward",
+			want: "api/v1/namespaces/{namespace}/pods/{name}/portforward",
+		},
+		{
+			name: "proxy subresource with a deep caller-chosen suffix",
+			verb: "GET",
+			path: "/api/v1/namespaces/default/pods/foo/proxy/some/deep/custom/route",
+			want: "api/v1/namespaces/{namespace}/pods/{name}/proxy",
+		},
+		{
+			name: "proxy subresource with no suffix",
+			verb: "GET",
+			path: "/api/v1/namespaces/default/services/foo/proxy",
+			want: "api/v1/namespaces/{namespace}/services/{name}/proxy",
+		},
+		{
+			// Known limitation: this pre-1.0 path style is not given
+			// special handling, so "watch" is read as the resource
+			// plural and "pods" as the resource "{name}" rather than as
+			// a list-level watch on the pods collection. It still
+			// normalizes to a single bounded-cardinality template, which
+			// is what this test pins down.
+			name: "deprecated pre-1.0 watch prefix is not specially handled",
+			verb: "WATCH",
+			path: "/api/v1/watch/pods",
+			want: "api/v1/watch/{name}",
+		},
+		{
+			name: "unrecognized root",
+			verb: "GET",
+			path: "/healthz",
+			want: unknownPathLabel,
+		},
+		{
+			name: "empty path",
+			verb: "GET",
+			path: "/",
+			want: unknownPathLabel,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			u := url.URL{Path: c.path, RawQuery: "resourceVersion=12345&watch=true"}
+			if got := (DefaultURLNormalizer{}).Normalize(c.verb, u); got != c.want {
+				t.Errorf("Normalize(%q, %q) = %q, want %q", c.verb, c.path, got, c.want)
+			}
+		})
+	}
+}
+
+// TestDefaultURLNormalizerBoundsProxyCardinality guards against the proxy
+// subresource's caller-chosen suffix leaking into the label: every proxy
+// request against the same resource must normalize to the same value
+// regardless of where the proxied request was ultimately headed.
+func TestDefaultURLNormalizerBoundsProxyCardinality(t *testing.T) {
+	norm := DefaultURLNormalizer{}
+	first := norm.Normalize("GET", url.URL{Path: "/api/v1/namespaces/default/pods/foo/proxy/aaaa"})
+	second := norm.Normalize("GET", url.URL{Path: "/api/v1/namespaces/default/pods/foo/proxy/bbbb/cccc/dddd"})
+	if first != second {
+		t.Fatalf("expected distinct proxy targets to normalize to the same label, got %q and %q", first, second)
+	}
+}